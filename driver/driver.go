@@ -0,0 +1,142 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package driver wires the provider's gRPC server and its auxiliary HTTP
+// servers (health, metrics) into a single lifecycle, so that main.go is
+// just construction and can be tested without binding real sockets.
+package driver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/GoogleCloudPlatform/secrets-store-csi-driver-provider-gcp/metrics"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"k8s.io/klog/v2"
+)
+
+// Driver runs a provider gRPC server and any number of auxiliary HTTP
+// servers under one errgroup: if any one of them fails, Run tears the
+// others down and returns the first error.
+type Driver struct {
+	// Listener is the socket the gRPC server accepts connections on.
+	Listener net.Listener
+	// GRPCServer is constructed by the caller so server options (e.g.
+	// interceptors) are injected there, not here.
+	GRPCServer *grpc.Server
+	// Registrars are called against GRPCServer before Run starts serving,
+	// one per service registered on the socket (e.g. each provider API
+	// version and the gRPC health service).
+	Registrars []func(*grpc.Server)
+	// HTTPServers are shut down alongside GRPCServer, e.g. health and
+	// metrics listeners.
+	HTTPServers []*http.Server
+	// BeforeShutdown, if set, runs once before GracefulStop is invoked,
+	// e.g. to flip readiness to failing ahead of the drain.
+	BeforeShutdown func()
+	// Started, if set, is closed immediately before GRPCServer.Serve is
+	// called on Listener, so a caller can delay reporting readiness until
+	// then. It does not wait for Serve to accept a connection.
+	Started chan struct{}
+	// ShutdownTimeout bounds how long Run waits for GracefulStop to drain
+	// in-flight RPCs before forcing a Stop. Zero means wait indefinitely.
+	ShutdownTimeout time.Duration
+	// InFlight, if set, is consulted to log how many RPCs were abandoned
+	// when ShutdownTimeout forces a Stop.
+	InFlight *metrics.InFlightTracker
+	// SocketPath, if set, is unlinked after a forced Stop so a restart
+	// doesn't inherit a stale socket left by abandoned connections.
+	SocketPath string
+}
+
+// Run registers all providers, starts serving, and blocks until ctx is
+// cancelled, at which point it gracefully stops the gRPC server and shuts
+// down the HTTP servers. It returns the first error encountered by any
+// component, or nil if ctx cancellation was the only reason Run returned.
+func (d *Driver) Run(ctx context.Context) error {
+	for _, register := range d.Registrars {
+		register(d.GRPCServer)
+	}
+
+	eg, egCtx := errgroup.WithContext(ctx)
+
+	eg.Go(func() error {
+		if d.Started != nil {
+			close(d.Started)
+		}
+		return d.GRPCServer.Serve(d.Listener)
+	})
+
+	for _, hs := range d.HTTPServers {
+		hs := hs
+		eg.Go(func() error {
+			if err := hs.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				return err
+			}
+			return nil
+		})
+	}
+
+	eg.Go(func() error {
+		<-egCtx.Done()
+		if d.BeforeShutdown != nil {
+			d.BeforeShutdown()
+		}
+		d.drainGRPCServer()
+		for _, hs := range d.HTTPServers {
+			_ = hs.Shutdown(context.Background())
+		}
+		return nil
+	})
+
+	return eg.Wait()
+}
+
+// drainGRPCServer calls GracefulStop, racing it against ShutdownTimeout. If
+// the timeout wins, it forces a Stop so in-flight RPCs are cancelled and the
+// pod is not left waiting for kubelet to SIGKILL it, logs how many RPCs were
+// abandoned, and unlinks SocketPath so a restart doesn't inherit stale
+// socket state.
+func (d *Driver) drainGRPCServer() {
+	if d.ShutdownTimeout <= 0 {
+		d.GRPCServer.GracefulStop()
+		return
+	}
+
+	graceful := make(chan struct{})
+	go func() {
+		d.GRPCServer.GracefulStop()
+		close(graceful)
+	}()
+
+	select {
+	case <-graceful:
+	case <-time.After(d.ShutdownTimeout):
+		abandoned := int64(0)
+		if d.InFlight != nil {
+			abandoned = d.InFlight.Count()
+		}
+		klog.InfoS("shutdown timeout exceeded, forcing stop", "timeout", d.ShutdownTimeout, "abandonedRPCs", abandoned)
+		d.GRPCServer.Stop()
+		if d.SocketPath != "" {
+			if err := os.Remove(d.SocketPath); err != nil && !os.IsNotExist(err) {
+				klog.ErrorS(err, "unable to unlink socket after forced stop", "path", d.SocketPath)
+			}
+		}
+	}
+}
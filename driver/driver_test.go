@@ -0,0 +1,185 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package driver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/secrets-store-csi-driver-provider-gcp/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// blockingServiceDesc describes a minimal gRPC service with a single streaming method
+// that blocks until its context is cancelled, used to simulate a Mount RPC
+// stuck on a slow Secret Manager call.
+var blockingServiceDesc = grpc.ServiceDesc{
+	ServiceName: "driver.test.Blocking",
+	HandlerType: (*any)(nil),
+	Streams: []grpc.StreamDesc{{
+		StreamName: "Block",
+		Handler: func(srv interface{}, stream grpc.ServerStream) error {
+			<-stream.Context().Done()
+			return stream.Context().Err()
+		},
+		ServerStreams: true,
+		ClientStreams: true,
+	}},
+}
+
+func TestRunStopsAllComponentsOnContextCancel(t *testing.T) {
+	lis := bufconn.Listen(1024 * 1024)
+
+	var registered bool
+	d := &Driver{
+		Listener:   lis,
+		GRPCServer: grpc.NewServer(),
+		Registrars: []func(*grpc.Server){
+			func(*grpc.Server) { registered = true },
+		},
+		HTTPServers: []*http.Server{{Addr: "127.0.0.1:0", Handler: http.NotFoundHandler()}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- d.Run(ctx) }()
+
+	// Give Run a moment to start serving before cancelling.
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run() = %v, want nil after context cancellation", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run() did not return within 2s of context cancellation")
+	}
+
+	if !registered {
+		t.Error("Run() did not invoke the registrar before serving")
+	}
+}
+
+func TestRunClosesStartedBeforeServing(t *testing.T) {
+	lis := bufconn.Listen(1024 * 1024)
+	started := make(chan struct{})
+
+	d := &Driver{
+		Listener:   lis,
+		GRPCServer: grpc.NewServer(),
+		Started:    started,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- d.Run(ctx) }()
+
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run() did not close Started before Serve")
+	}
+
+	cancel()
+	<-done
+}
+
+func TestDrainGRPCServerForcesStopAfterTimeout(t *testing.T) {
+	lis := bufconn.Listen(1024 * 1024)
+
+	g := grpc.NewServer()
+	g.RegisterService(&blockingServiceDesc, nil)
+	go g.Serve(lis)
+
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.Dial()
+	}))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	stream, err := conn.NewStream(context.Background(), &blockingServiceDesc.Streams[0], "/driver.test.Blocking/Block")
+	if err != nil {
+		t.Fatalf("NewStream: %v", err)
+	}
+	// Send a first message so the stream is on the wire ahead of the drain's
+	// GOAWAY and is in flight on the server when drainGRPCServer runs.
+	if err := stream.SendMsg(&emptypb.Empty{}); err != nil {
+		t.Fatalf("SendMsg: %v", err)
+	}
+
+	socketPath := filepath.Join(t.TempDir(), "gcp.sock")
+	if err := os.WriteFile(socketPath, nil, 0600); err != nil {
+		t.Fatalf("seed socket file: %v", err)
+	}
+
+	d := &Driver{
+		GRPCServer:      g,
+		ShutdownTimeout: 50 * time.Millisecond,
+		InFlight:        &metrics.InFlightTracker{},
+		SocketPath:      socketPath,
+	}
+
+	done := make(chan struct{})
+	go func() {
+		d.drainGRPCServer()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("drainGRPCServer did not return after its timeout elapsed")
+	}
+
+	if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
+		t.Errorf("socket file still exists after forced stop: err=%v", err)
+	}
+}
+
+func TestRunTearsDownOnComponentFailure(t *testing.T) {
+	lis := bufconn.Listen(1024 * 1024)
+	// Close the listener immediately so GRPCServer.Serve fails fast and Run
+	// should propagate that failure rather than hang.
+	lis.Close()
+
+	d := &Driver{
+		Listener:   lis,
+		GRPCServer: grpc.NewServer(),
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- d.Run(context.Background()) }()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Run() = nil, want error from a closed listener")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run() did not return after its listener failed")
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package health implements liveness and readiness checks for the provider,
+// exposed both over HTTP (for a kubelet httpGet probe) and over the provider
+// gRPC socket (for a sidecar that speaks grpc.health.v1.Health, mirroring
+// csi-livenessprobe).
+package health
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"k8s.io/klog/v2"
+)
+
+// PingFunc performs a cheap, bounded check that the provider can still reach
+// its upstream dependencies (e.g. the Secret Manager API).
+type PingFunc func(ctx context.Context) error
+
+// Checker tracks the provider's liveness and readiness. Readiness is a
+// simple flag flipped by the caller around the gRPC server's lifecycle;
+// liveness additionally verifies the provider's Unix socket is still present
+// on disk and caches the result of PingFunc so that repeated probes don't
+// hammer the upstream API.
+type Checker struct {
+	socketPath   string
+	ping         PingFunc
+	pingInterval time.Duration
+	grpcHealth   *health.Server
+
+	mu       sync.Mutex
+	ready    bool
+	lastPing time.Time
+	lastErr  error
+}
+
+// NewChecker returns a Checker for the provider listening on socketPath.
+// ping is invoked at most once per pingInterval; the cached result is
+// reused for any probes in between.
+func NewChecker(socketPath string, pingInterval time.Duration, ping PingFunc) *Checker {
+	return &Checker{
+		socketPath:   socketPath,
+		ping:         ping,
+		pingInterval: pingInterval,
+		grpcHealth:   health.NewServer(),
+	}
+}
+
+// SetReady updates the readiness state reported by ReadyzHandler and the
+// grpc.health.v1.Health service registered via RegisterGRPC.
+func (c *Checker) SetReady(ready bool) {
+	c.mu.Lock()
+	c.ready = ready
+	c.mu.Unlock()
+
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if ready {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+	c.grpcHealth.SetServingStatus("", status)
+}
+
+// RegisterGRPC registers the grpc.health.v1.Health service on g so a
+// sidecar probe can query provider health over the same Unix socket used
+// for Mount RPCs.
+func (c *Checker) RegisterGRPC(g *grpc.Server) {
+	healthpb.RegisterHealthServer(g, c.grpcHealth)
+}
+
+// ReadyzHandler reports whether the gRPC server is currently accepting
+// Mount RPCs.
+func (c *Checker) ReadyzHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c.mu.Lock()
+		ready := c.ready
+		c.mu.Unlock()
+
+		if !ready {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	}
+}
+
+// LivezHandler reports whether the provider's socket still exists on disk
+// and the provider can still reach Secret Manager, refreshing the latter at
+// most once per pingInterval.
+func (c *Checker) LivezHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := os.Stat(c.socketPath); err != nil {
+			klog.ErrorS(err, "livez: provider socket missing", "path", c.socketPath)
+			http.Error(w, "socket missing", http.StatusServiceUnavailable)
+			return
+		}
+
+		if err := c.cachedPing(r.Context()); err != nil {
+			klog.ErrorS(err, "livez: secret manager unreachable")
+			http.Error(w, "upstream unreachable", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	}
+}
+
+func (c *Checker) cachedPing(ctx context.Context) error {
+	c.mu.Lock()
+	stale := time.Since(c.lastPing) >= c.pingInterval
+	if !stale {
+		defer c.mu.Unlock()
+		return c.lastErr
+	}
+	c.mu.Unlock()
+
+	err := c.ping(ctx)
+
+	c.mu.Lock()
+	c.lastPing = time.Now()
+	c.lastErr = err
+	c.mu.Unlock()
+
+	return err
+}
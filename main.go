@@ -22,12 +22,19 @@ import (
 	"flag"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"syscall"
+	"time"
 
+	"github.com/GoogleCloudPlatform/secrets-store-csi-driver-provider-gcp/driver"
+	"github.com/GoogleCloudPlatform/secrets-store-csi-driver-provider-gcp/health"
+	"github.com/GoogleCloudPlatform/secrets-store-csi-driver-provider-gcp/metrics"
 	"github.com/GoogleCloudPlatform/secrets-store-csi-driver-provider-gcp/server"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/oauth2/google"
 	"google.golang.org/grpc"
 	jlogs "k8s.io/component-base/logs/json"
 	"k8s.io/klog/v2"
@@ -35,12 +42,19 @@ import (
 )
 
 var (
-	kubeconfig    = flag.String("kubeconfig", "", "absolute path to kubeconfig file")
-	logFormatJSON = flag.Bool("log-format-json", true, "set log formatter to json")
+	kubeconfig      = flag.String("kubeconfig", "", "absolute path to kubeconfig file")
+	logFormatJSON   = flag.Bool("log-format-json", true, "set log formatter to json")
+	healthAddr      = flag.String("health-addr", ":8080", "configure http listener for reporting health")
+	metricsAddr     = flag.String("metrics-addr", ":8095", "configure http listener for reporting metrics")
+	shutdownTimeout = flag.Duration("shutdown-timeout", 30*time.Second, "max time to wait for in-flight RPCs to drain before forcing shutdown")
 
 	version = "dev"
 )
 
+// livenessPingInterval bounds how often /livez is allowed to actually probe
+// the Secret Manager API; more frequent probes reuse the cached result.
+const livenessPingInterval = 30 * time.Second
+
 func main() {
 	klog.InitFlags(nil)
 	defer klog.Flush()
@@ -72,13 +86,67 @@ func main() {
 	}
 	defer l.Close()
 
-	g := grpc.NewServer()
-	v1alpha1.RegisterCSIDriverProviderServer(g, s)
-	go g.Serve(l)
+	checker := health.NewChecker(socketPath, livenessPingInterval, pingSecretManager)
+
+	healthMux := http.NewServeMux()
+	healthMux.Handle("/livez", checker.LivezHandler())
+	healthMux.Handle("/readyz", checker.ReadyzHandler())
+
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/metrics", promhttp.Handler())
+
+	inFlight := &metrics.InFlightTracker{}
+	started := make(chan struct{})
+
+	d := &driver.Driver{
+		Listener: l,
+		GRPCServer: grpc.NewServer(grpc.ChainUnaryInterceptor(
+			metrics.UnaryServerInterceptor(),
+			inFlight.UnaryServerInterceptor(),
+		)),
+		Registrars: []func(*grpc.Server){
+			func(g *grpc.Server) { v1alpha1.RegisterCSIDriverProviderServer(g, s) },
+			checker.RegisterGRPC,
+		},
+		HTTPServers: []*http.Server{
+			{Addr: *healthAddr, Handler: healthMux},
+			{Addr: *metricsAddr, Handler: metricsMux},
+		},
+		BeforeShutdown:  func() { checker.SetReady(false) },
+		ShutdownTimeout: *shutdownTimeout,
+		InFlight:        inFlight,
+		SocketPath:      socketPath,
+		Started:         started,
+	}
+
+	go func() {
+		<-ctx.Done()
+		klog.InfoS("terminating")
+	}()
+	go func() {
+		<-started
+		checker.SetReady(true)
+	}()
+	if err := d.Run(ctx); err != nil {
+		klog.ErrorS(err, "provider exited unexpectedly")
+		klog.Fatalln("unable to continue")
+	}
+}
 
-	<-ctx.Done()
-	klog.InfoS("terminating")
-	g.GracefulStop()
+// pingSecretManager performs a cheap check that the provider can still
+// reach the Secret Manager API's auth path, used to back the /livez probe.
+// Resolving default credentials alone only reads a local file and never
+// touches the network; exchanging them for an access token does, so a
+// firewalled or unreachable token endpoint fails the probe as intended.
+func pingSecretManager(ctx context.Context) error {
+	ts, err := google.DefaultTokenSource(ctx, "https://www.googleapis.com/auth/cloud-platform")
+	if err != nil {
+		return fmt.Errorf("unable to resolve default token source: %w", err)
+	}
+	if _, err := ts.Token(); err != nil {
+		return fmt.Errorf("unable to exchange for an access token: %w", err)
+	}
+	return nil
 }
 
 // withShutdownSignal returns a copy of the parent context that will close if
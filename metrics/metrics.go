@@ -0,0 +1,162 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics instruments the provider's gRPC surface and its calls to
+// the Secret Manager API with Prometheus metrics, and provides a gRPC
+// interceptor that logs each RPC with a request ID, analogous to the
+// protosanitizer pattern used elsewhere in the CSI ecosystem.
+package metrics
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/secrets-store-csi-driver/provider/v1alpha1"
+)
+
+var (
+	// RPCCount counts provider gRPC calls by method and result code.
+	RPCCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "provider_gcp_rpc_count",
+		Help: "Number of provider gRPC calls by method and result code.",
+	}, []string{"method", "code"})
+
+	// RPCLatency records provider gRPC call latency by method.
+	RPCLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "provider_gcp_rpc_latency_seconds",
+		Help:    "Latency of provider gRPC calls by method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+
+	// SecretManagerRequestCount counts calls made to the Secret Manager API
+	// by result code.
+	SecretManagerRequestCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "provider_gcp_secretmanager_request_count",
+		Help: "Number of Secret Manager API calls by result code.",
+	}, []string{"code"})
+
+	// SecretManagerRequestLatency records Secret Manager API call latency.
+	SecretManagerRequestLatency = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "provider_gcp_secretmanager_request_latency_seconds",
+		Help:    "Latency of Secret Manager API calls.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	// SecretCacheHitRatio tracks the fraction of secret version lookups
+	// served from cache, once a cache is introduced.
+	SecretCacheHitRatio = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "provider_gcp_secret_cache_hit_ratio",
+		Help: "Fraction of secret version lookups served from cache.",
+	})
+
+	// AuthTokenLatency records how long exchanging for an access token took.
+	AuthTokenLatency = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "provider_gcp_auth_token_latency_seconds",
+		Help:    "Latency of auth token exchanges.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	// MountDuration records how long a Mount RPC took per SecretProviderClass.
+	MountDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "provider_gcp_mount_duration_seconds",
+		Help:    "Duration of Mount RPCs by SecretProviderClass.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"spc"})
+)
+
+// requestIDKey is the context key under which the per-RPC request ID set by
+// UnaryServerInterceptor is stored.
+type requestIDKey struct{}
+
+// RequestID returns the request ID associated with ctx, if any.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// UnaryServerInterceptor records per-method call count and latency, and logs
+// each RPC with a request ID and sanitized parameters.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		reqID := uuid.NewString()
+		ctx = context.WithValue(ctx, requestIDKey{}, reqID)
+
+		start := time.Now()
+		klog.InfoS("handling RPC", "method", info.FullMethod, "requestID", reqID, "params", sanitize(req))
+
+		resp, err := handler(ctx, req)
+
+		RPCLatency.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+		RPCCount.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+
+		if err != nil {
+			klog.ErrorS(err, "RPC failed", "method", info.FullMethod, "requestID", reqID, "code", status.Code(err))
+		}
+		return resp, err
+	}
+}
+
+// redactedSecrets is logged in place of the Secrets field of a MountRequest,
+// which carries the pod's Kubernetes Secret payload (not to be confused with
+// the Secret Manager resource names in Attributes, which are safe to log).
+const redactedSecrets = "<redacted>"
+
+// sanitize returns a representation of req with any secret payload values
+// redacted. Secret resource names (e.g. projects/.../secrets/...) are safe
+// to log; the values stored in those secrets are not.
+func sanitize(req interface{}) interface{} {
+	switch r := req.(type) {
+	case *v1alpha1.MountRequest:
+		cp := *r
+		cp.Secrets = redactedSecrets
+		return &cp
+	default:
+		return req
+	}
+}
+
+// RecordSecretManagerCall instruments a single Secret Manager API call's
+// latency and result code.
+func RecordSecretManagerCall(latency time.Duration, err error) {
+	SecretManagerRequestLatency.Observe(latency.Seconds())
+	SecretManagerRequestCount.WithLabelValues(status.Code(err).String()).Inc()
+}
+
+// InFlightTracker counts RPCs currently being handled, so a forced shutdown
+// can report how many were abandoned mid-flight.
+type InFlightTracker struct {
+	n int64
+}
+
+// UnaryServerInterceptor increments the in-flight count for the duration of
+// each RPC.
+func (t *InFlightTracker) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		atomic.AddInt64(&t.n, 1)
+		defer atomic.AddInt64(&t.n, -1)
+		return handler(ctx, req)
+	}
+}
+
+// Count returns the number of RPCs currently in flight.
+func (t *InFlightTracker) Count() int64 {
+	return atomic.LoadInt64(&t.n)
+}
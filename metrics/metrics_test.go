@@ -0,0 +1,101 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"google.golang.org/grpc"
+	"sigs.k8s.io/secrets-store-csi-driver/provider/v1alpha1"
+)
+
+func TestSanitizeRedactsSecretPayloads(t *testing.T) {
+	req := &v1alpha1.MountRequest{Attributes: "{}", Secrets: "super-secret-value", TargetPath: "/tmp/target"}
+	got, ok := sanitize(req).(*v1alpha1.MountRequest)
+	if !ok {
+		t.Fatalf("sanitize(%T) = %T, want *v1alpha1.MountRequest", req, got)
+	}
+	if got.Secrets == req.Secrets {
+		t.Errorf("sanitize() did not redact v1alpha1.MountRequest.Secrets: %v", got.Secrets)
+	}
+	if got.Attributes != req.Attributes || got.TargetPath != req.TargetPath {
+		t.Errorf("sanitize() altered non-secret fields: got %+v, want Attributes/TargetPath unchanged from %+v", got, req)
+	}
+}
+
+func TestSanitizePassesThroughNonSecretRequests(t *testing.T) {
+	type versionRequest struct{ Version string }
+	req := versionRequest{Version: "v1alpha1"}
+	if got := sanitize(req); got != req {
+		t.Errorf("sanitize(%v) = %v, want unchanged", req, got)
+	}
+}
+
+func TestUnaryServerInterceptorRecordsRequestID(t *testing.T) {
+	var sawID string
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		sawID = RequestID(ctx)
+		return nil, nil
+	}
+
+	_, err := UnaryServerInterceptor()(context.Background(), struct{}{}, &grpc.UnaryServerInfo{FullMethod: "/test/Method"}, handler)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sawID == "" {
+		t.Error("UnaryServerInterceptor did not propagate a request ID to the handler context")
+	}
+}
+
+func TestUnaryServerInterceptorPropagatesHandlerError(t *testing.T) {
+	wantErr := errors.New("boom")
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, wantErr
+	}
+
+	_, err := UnaryServerInterceptor()(context.Background(), struct{}{}, &grpc.UnaryServerInfo{FullMethod: "/test/Method"}, handler)
+	if !errors.Is(err, wantErr) {
+		t.Errorf("got err %v, want %v", err, wantErr)
+	}
+}
+
+func TestInFlightTrackerCountsConcurrentRPCs(t *testing.T) {
+	var tracker InFlightTracker
+	release := make(chan struct{})
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		<-release
+		return nil, nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		tracker.UnaryServerInterceptor()(context.Background(), struct{}{}, &grpc.UnaryServerInfo{FullMethod: "/test/Method"}, handler)
+		close(done)
+	}()
+
+	for tracker.Count() == 0 {
+	}
+	if got := tracker.Count(); got != 1 {
+		t.Errorf("Count() = %d while RPC in flight, want 1", got)
+	}
+
+	close(release)
+	<-done
+	if got := tracker.Count(); got != 0 {
+		t.Errorf("Count() = %d after RPC completed, want 0", got)
+	}
+}
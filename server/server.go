@@ -0,0 +1,155 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package server implements the secrets-store-csi-driver provider gRPC
+// service, fetching secrets from Google Cloud's Secret Manager API and
+// writing them to the path requested by the driver.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"time"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	secretmanagerpb "cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/GoogleCloudPlatform/secrets-store-csi-driver-provider-gcp/metrics"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/secrets-store-csi-driver/provider/v1alpha1"
+)
+
+// Server implements the v1alpha1 provider gRPC service. It is constructed
+// once in main and registered against the v1alpha1 provider API.
+type Server struct {
+	UA         string
+	Kubeconfig string
+
+	// accessSecretVersion, if set, is called instead of constructing a real
+	// Secret Manager client, letting tests exercise mount's success path
+	// against a fake. Left nil in production.
+	accessSecretVersion func(ctx context.Context, req *secretmanagerpb.AccessSecretVersionRequest) (*secretmanagerpb.AccessSecretVersionResponse, error)
+}
+
+// secretSpec is a single entry of the SecretProviderClass `objects` array,
+// as passed to Mount in the request's Attributes.
+type secretSpec struct {
+	ResourceName string `json:"resourceName"`
+	FileName     string `json:"fileName"`
+}
+
+// mountedFile is a file to be written to the target path requested by Mount.
+type mountedFile struct {
+	path     string
+	mode     int32
+	contents []byte
+	version  string
+	id       string
+}
+
+// Mount implements the v1alpha1 provider API.
+func (s *Server) Mount(ctx context.Context, req *v1alpha1.MountRequest) (*v1alpha1.MountResponse, error) {
+	files, err := s.mount(ctx, req.GetAttributes(), req.GetSecrets(), req.GetTargetPath(), req.GetPermission())
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &v1alpha1.MountResponse{}
+	for _, f := range files {
+		resp.Files = append(resp.Files, &v1alpha1.File{
+			Path:     f.path,
+			Mode:     f.mode,
+			Contents: f.contents,
+		})
+		resp.ObjectVersion = append(resp.ObjectVersion, &v1alpha1.ObjectVersion{
+			Id:      f.id,
+			Version: f.version,
+		})
+	}
+	return resp, nil
+}
+
+// Version implements the v1alpha1 provider API.
+func (s *Server) Version(ctx context.Context, req *v1alpha1.VersionRequest) (*v1alpha1.VersionResponse, error) {
+	return &v1alpha1.VersionResponse{
+		Version:        "v1alpha1",
+		RuntimeName:    "secrets-store-csi-driver-provider-gcp",
+		RuntimeVersion: s.UA,
+	}, nil
+}
+
+// mount is the core of Mount: it parses the SecretProviderClass attributes,
+// fetches each referenced secret version from Secret Manager, and returns
+// the files to write. It is split out from Mount so the fetch/parse logic
+// can be tested directly against a fake accessSecretVersion. ctx is the
+// RPC's own context, so a forced server Stop during shutdown cancels it and
+// aborts any in-flight Secret Manager call instead of hanging the drain.
+func (s *Server) mount(ctx context.Context, attributes, secrets, targetPath, permission string) ([]mountedFile, error) {
+	var params struct {
+		Objects string `json:"objects"`
+		// SecretProviderClassName, if the driver includes it in the mount
+		// attributes, labels MountDuration so per-class mount latency can
+		// be broken out on /metrics.
+		SecretProviderClassName string `json:"csi.storage.k8s.io/secretProviderClassName"`
+	}
+	if err := json.Unmarshal([]byte(attributes), &params); err != nil {
+		return nil, fmt.Errorf("unable to parse attributes: %w", err)
+	}
+
+	spc := params.SecretProviderClassName
+	if spc == "" {
+		spc = "unknown"
+	}
+	mountStart := time.Now()
+	defer func() { metrics.MountDuration.WithLabelValues(spc).Observe(time.Since(mountStart).Seconds()) }()
+
+	var specs []secretSpec
+	if err := json.Unmarshal([]byte(params.Objects), &specs); err != nil {
+		return nil, fmt.Errorf("unable to parse objects: %w", err)
+	}
+
+	access := s.accessSecretVersion
+	if access == nil {
+		authStart := time.Now()
+		client, err := secretmanager.NewClient(ctx)
+		metrics.AuthTokenLatency.Observe(time.Since(authStart).Seconds())
+		if err != nil {
+			return nil, fmt.Errorf("unable to create secretmanager client: %w", err)
+		}
+		defer client.Close()
+		access = client.AccessSecretVersion
+	}
+
+	var files []mountedFile
+	for _, spec := range specs {
+		start := time.Now()
+		sm, err := access(ctx, &secretmanagerpb.AccessSecretVersionRequest{Name: spec.ResourceName})
+		metrics.RecordSecretManagerCall(time.Since(start), err)
+		if err != nil {
+			return nil, fmt.Errorf("unable to access secret version %q: %w", spec.ResourceName, err)
+		}
+
+		files = append(files, mountedFile{
+			path:     spec.FileName,
+			mode:     0440,
+			contents: sm.GetPayload().GetData(),
+			id:       spec.ResourceName,
+			version:  sm.GetName(),
+		})
+	}
+
+	klog.V(5).InfoS("mounted secrets", "requestID", metrics.RequestID(ctx), "targetPath", targetPath, "count", len(files))
+	return files, nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"testing"
+
+	secretmanagerpb "cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"sigs.k8s.io/secrets-store-csi-driver/provider/v1alpha1"
+)
+
+// fakeAccessSecretVersion returns a deterministic response derived from the
+// requested resource name, so tests can assert on exact file contents and
+// object versions without calling the live Secret Manager API.
+func fakeAccessSecretVersion(ctx context.Context, req *secretmanagerpb.AccessSecretVersionRequest) (*secretmanagerpb.AccessSecretVersionResponse, error) {
+	return &secretmanagerpb.AccessSecretVersionResponse{
+		Name:    req.GetName() + "/versions/1",
+		Payload: &secretmanagerpb.SecretPayload{Data: []byte("fake-secret-value")},
+	}, nil
+}
+
+func TestMountReturnsFilesFromSecretManager(t *testing.T) {
+	s := &Server{UA: "test", accessSecretVersion: fakeAccessSecretVersion}
+
+	const attributes = `{"objects":"[{\"resourceName\":\"projects/p/secrets/s/versions/latest\",\"fileName\":\"out\"}]"}`
+
+	resp, err := s.Mount(context.Background(), &v1alpha1.MountRequest{
+		Attributes: attributes,
+		TargetPath: "/tmp/target",
+		Permission: "0440",
+	})
+	if err != nil {
+		t.Fatalf("Mount() error = %v", err)
+	}
+
+	if len(resp.GetFiles()) != 1 {
+		t.Fatalf("want 1 file, got %d", len(resp.GetFiles()))
+	}
+	if got, want := string(resp.Files[0].Contents), "fake-secret-value"; got != want {
+		t.Errorf("Mount() file contents = %q, want %q", got, want)
+	}
+	if got, want := resp.ObjectVersion[0].Version, "projects/p/secrets/s/versions/latest/versions/1"; got != want {
+		t.Errorf("Mount() object version = %q, want %q", got, want)
+	}
+}
+
+func TestMountReturnsErrorOnUnparsableObjects(t *testing.T) {
+	s := &Server{UA: "test"}
+
+	_, err := s.Mount(context.Background(), &v1alpha1.MountRequest{
+		Attributes: `{"objects":"not-valid-json-on-purpose"}`,
+		TargetPath: "/tmp/target",
+		Permission: "0440",
+	})
+	if err == nil {
+		t.Fatal("Mount() error = nil, want error for unparsable objects")
+	}
+}
+
+func TestVersionReportsV1Alpha1(t *testing.T) {
+	s := &Server{UA: "test"}
+
+	resp, err := s.Version(context.Background(), &v1alpha1.VersionRequest{})
+	if err != nil {
+		t.Fatalf("Version() error = %v", err)
+	}
+	if got, want := resp.GetVersion(), "v1alpha1"; got != want {
+		t.Errorf("Version() = %q, want %q", got, want)
+	}
+}